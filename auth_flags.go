@@ -0,0 +1,68 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vaidikcode/Pull-Time/pkg/auth"
+)
+
+var (
+	authUsername   string
+	authPassword   string
+	authConfigPath string
+	authAnonymous  bool
+)
+
+var (
+	authStoreOnce sync.Once
+	authStore     *auth.Store
+)
+
+// loadedAuthStore lazily loads the docker config file once per run, so
+// every pull-issuing command shares the same credential helper results.
+func loadedAuthStore() *auth.Store {
+	authStoreOnce.Do(func() {
+		path := auth.ConfigPath(authConfigPath)
+		store, err := auth.LoadStore(path)
+		if err != nil {
+			store = &auth.Store{}
+		}
+		authStore = store
+	})
+	return authStore
+}
+
+// resolveCredential picks a credential for registryHost in the same order
+// the Docker CLI would: explicit --username/--password, then --anonymous
+// (which always wins and forces no credential), then the docker config
+// file (credential helpers, credsStore, inline auths).
+func resolveCredential(registryHost string) auth.Credential {
+	if authAnonymous {
+		return auth.Credential{}
+	}
+	if authUsername != "" {
+		return auth.Credential{Username: authUsername, Password: authPassword}
+	}
+	cred, ok, err := loadedAuthStore().CredentialFor(registryHost)
+	if err != nil || !ok {
+		return auth.Credential{}
+	}
+	return cred
+}
+
+// addAuthFlags registers the shared --username/--password/--auth-config/
+// --anonymous flags on a pull-issuing command.
+func addAuthFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&authUsername, "username", "", "Registry username (enables Basic auth for the token exchange)")
+	cmd.Flags().StringVar(&authPassword, "password", "", "Registry password or token, used with --username")
+	cmd.Flags().StringVar(&authConfigPath, "auth-config", "", "Path to a docker config.json to read credentials from (default: $DOCKER_CONFIG/config.json or ~/.docker/config.json)")
+	cmd.Flags().BoolVar(&authAnonymous, "anonymous", false, "Never resolve credentials, even if one is configured")
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{imageCmd, benchmarkCmd, compareCmd, ciCmd, warmupCmd, mirrorsCmd} {
+		addAuthFlags(cmd)
+	}
+}
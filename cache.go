@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+// layerCacheState classifies how much of an image's manifest is already
+// present in the local content store (our in-process blob cache, standing
+// in for `docker image inspect`/the registry client's own store) before a
+// pull is attempted.
+type layerCacheState struct {
+	State        string // "cached", "partial", "absent"
+	CachedBytes  int64
+	MissingBytes int64
+}
+
+// classifyCacheState resolves imageURL's manifest and checks each layer
+// digest against the local cache without fetching any blob bytes, so
+// warmup can report an accurate cache state instead of assuming the first
+// iteration is always cold.
+func classifyCacheState(imageURL string) (layerCacheState, error) {
+	ref := parseImageRef(imageURL)
+	client := newRegistryClient(resolveCredential(ref.Registry))
+	m, err := client.manifest(ref)
+	if err != nil {
+		return layerCacheState{}, err
+	}
+
+	var cached, missing int
+	var cachedBytes, missingBytes int64
+	for _, layer := range m.Layers {
+		if isDigestCached(layer.Digest) {
+			cached++
+			cachedBytes += layer.Size
+		} else {
+			missing++
+			missingBytes += layer.Size
+		}
+	}
+
+	state := layerCacheState{CachedBytes: cachedBytes, MissingBytes: missingBytes}
+	switch {
+	case missing == 0 && cached > 0:
+		state.State = "cached"
+	case cached == 0:
+		state.State = "absent"
+	default:
+		state.State = "partial"
+	}
+	return state, nil
+}
+
+// coldModeReset prepares imageURL for a cold pull according to mode:
+//
+//   - "none" does nothing, leaving the local cache exactly as the last
+//     iteration left it, so classifyCacheState can report the iteration's
+//     real "cached"/"partial" state instead of the reset always forcing
+//     "absent".
+//   - "rmi" removes the image from the local docker daemon (the pre-existing
+//     behavior) and forgets its layer digests so the native puller treats
+//     them as absent.
+//   - "prune" additionally purges every digest the native puller has ever
+//     cached in this process, simulating a `docker system prune` of
+//     dangling blobs.
+//   - "drop-caches" additionally flushes the Linux page cache so disk-read
+//     time is measured rather than RAM, best-effort and Linux-only.
+func coldModeReset(imageURL, mode string) {
+	if mode == "none" {
+		return
+	}
+
+	exec.Command("bash", "-c", "docker rmi -f "+imageURL).Run()
+	forgetImageDigests(imageURL)
+
+	switch mode {
+	case "prune":
+		purgeLocalCache()
+	case "drop-caches":
+		purgeLocalCache()
+		if runtime.GOOS == "linux" {
+			exec.Command("bash", "-c", "sync && echo 3 > /proc/sys/vm/drop_caches").Run()
+		}
+	}
+}
+
+// forgetImageDigests removes imageURL's layer digests from the local cache
+// so a subsequent classifyCacheState call reports them as absent again.
+func forgetImageDigests(imageURL string) {
+	ref := parseImageRef(imageURL)
+	client := newRegistryClient(resolveCredential(ref.Registry))
+	m, err := client.manifest(ref)
+	if err != nil {
+		return
+	}
+	localBlobCache.Lock()
+	defer localBlobCache.Unlock()
+	for _, layer := range m.Layers {
+		delete(localBlobCache.digests, layer.Digest)
+	}
+}
+
+// purgeLocalCache clears every digest the native puller has cached in this
+// process, regardless of which image it belonged to.
+func purgeLocalCache() {
+	localBlobCache.Lock()
+	defer localBlobCache.Unlock()
+	localBlobCache.digests = map[string]bool{}
+}
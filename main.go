@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
-	"strings"
 	"sync"
 	"time"
 
@@ -26,16 +24,19 @@ var imageCmd = &cobra.Command{
 		imageURL := args[0]
 		fmt.Printf("Pulling image: %s\n", imageURL)
 		start := time.Now()
-		pullCmd := fmt.Sprintf("docker pull %s", imageURL)
-		output, err := runCommand(pullCmd)
+		puller, err := newPuller(runtimeBackend, concurrency, emitProgressJSON)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		pullResult, err := puller.Pull(imageURL)
 		if err != nil {
 			fmt.Printf("Error pulling image: %v\n", err)
 			os.Exit(1)
 		}
 		elapsed := time.Since(start)
 		fmt.Printf("Image pull completed in: %v\n", elapsed)
-		fmt.Println("--- Docker Output ---")
-		fmt.Println(output)
+		fmt.Printf("Layers: %d, bytes downloaded: %d\n", pullResult.LayerCount, pullResult.TotalBytes)
 	},
 }
 
@@ -43,6 +44,8 @@ var (
 	concurrency   int
 	timeoutSec    int
 	outputSummary bool
+	repeatCount   int
+	warmupCount   int
 )
 
 var benchmarkCmd = &cobra.Command{
@@ -50,8 +53,9 @@ var benchmarkCmd = &cobra.Command{
 	Short: "Benchmark pull times for multiple container images and output JSON report",
 	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		mirrorEndpoints, _ := cmd.Flags().GetStringArray("mirror")
 		var resultsMu sync.Mutex
-		results := make([]Result, 0, len(args))
+		results := make([]Result, 0, len(args)*repeatCount)
 		wg := sync.WaitGroup{}
 		sem := make(chan struct{}, concurrency)
 		for _, imageURL := range args {
@@ -59,42 +63,38 @@ var benchmarkCmd = &cobra.Command{
 			go func(imageURL string) {
 				defer wg.Done()
 				sem <- struct{}{}
-				ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
-				defer cancel()
-				start := time.Now()
-				pullCmd := fmt.Sprintf("docker pull %s", imageURL)
-				cmd := exec.CommandContext(ctx, "bash", "-c", pullCmd)
-				output, err := cmd.CombinedOutput()
-				elapsed := time.Since(start)
-				registry := parseRegistry(imageURL)
-				res := Result{
-					Image:      imageURL,
-					Registry:   registry,
-					Success:    err == nil && ctx.Err() == nil,
-					PullTimeMs: elapsed.Milliseconds(),
-					StartTime:  start.Format(time.RFC3339),
-					EndTime:    time.Now().Format(time.RFC3339),
-					CmdOutput:  string(output),
-				}
-				if err != nil {
-					res.Error = err.Error()
+				defer func() { <-sem }()
+				for i := 0; i < warmupCount+repeatCount; i++ {
+					res := pullOnce(imageURL, mirrorEndpoints)
+					if i < warmupCount {
+						continue
+					}
+					resultsMu.Lock()
+					results = append(results, res)
+					resultsMu.Unlock()
 				}
-				if ctx.Err() != nil {
-					res.Error = ctx.Err().Error()
-				}
-				// Parse output for more details (layers, bytes)
-				parseDockerOutput(&res, string(output))
-				resultsMu.Lock()
-				results = append(results, res)
-				resultsMu.Unlock()
-				<-sem
 			}(imageURL)
 		}
 		wg.Wait()
 		if outputSummary {
 			printSummary(results)
 		}
-		jsonOut, err := json.MarshalIndent(results, "", "  ")
+		report := struct {
+			Results   []Result                  `json:"results"`
+			Aggregate map[string]ImageAggregate `json:"aggregate,omitempty"`
+			Global    *GlobalAggregate          `json:"global,omitempty"`
+		}{Results: results}
+		if repeatCount > 1 {
+			perImage, global := computeAggregates(results)
+			report.Aggregate = perImage
+			report.Global = &global
+		}
+		if gatewayURL, _ := cmd.Flags().GetString("prometheus"); gatewayURL != "" {
+			if err := pushToGateway(gatewayURL, "pulltime_benchmark", "", formatMetrics(results)); err != nil {
+				fmt.Printf("Error pushing to Prometheus gateway: %v\n", err)
+			}
+		}
+		jsonOut, err := json.MarshalIndent(report, "", "  ")
 		if err != nil {
 			fmt.Printf("Error generating JSON: %v\n", err)
 			os.Exit(1)
@@ -103,6 +103,58 @@ var benchmarkCmd = &cobra.Command{
 	},
 }
 
+// pullOnce performs a single timed pull of imageURL (optionally also racing
+// it through mirror endpoints) and returns the resulting Result, respecting
+// the global --timeout flag.
+func pullOnce(imageURL string, mirrorEndpoints []string) Result {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+	start := time.Now()
+	type pullOutcome struct {
+		res nativePullResult
+		err error
+	}
+	outcome := make(chan pullOutcome, 1)
+	go func() {
+		puller, err := newPuller(runtimeBackend, concurrency, nil)
+		if err != nil {
+			outcome <- pullOutcome{err: err}
+			return
+		}
+		r, err := puller.Pull(imageURL)
+		outcome <- pullOutcome{res: r, err: err}
+	}()
+	res := Result{
+		Image:     imageURL,
+		Registry:  parseRegistry(imageURL),
+		StartTime: start.Format(time.RFC3339),
+		Backend:   runtimeBackend,
+	}
+	select {
+	case o := <-outcome:
+		elapsed := time.Since(start)
+		res.Success = o.err == nil
+		res.PullTimeMs = elapsed.Milliseconds()
+		res.EndTime = time.Now().Format(time.RFC3339)
+		res.Bytes = o.res.TotalBytes
+		res.Layers = len(o.res.Layers)
+		res.LayerTimings = o.res.Layers
+		if o.err != nil {
+			res.Error = o.err.Error()
+		}
+	case <-ctx.Done():
+		elapsed := time.Since(start)
+		res.Success = false
+		res.PullTimeMs = elapsed.Milliseconds()
+		res.EndTime = time.Now().Format(time.RFC3339)
+		res.Error = ctx.Err().Error()
+	}
+	if len(mirrorEndpoints) > 0 {
+		res.MirrorResults = pullThroughEndpoints(imageURL, mirrorEndpoints, concurrency)
+	}
+	return res
+}
+
 var compareCmd = &cobra.Command{
 	Use:   "compare [IMAGE_MIRROR] [IMAGE_REMOTE]",
 	Short: "Compare pull times between a mirror and a remote registry, outputting a JSON report",
@@ -117,10 +169,14 @@ var compareCmd = &cobra.Command{
 		}
 		images := []string{args[0], args[1]}
 		var results []CompareResult
+		puller, err := newPuller(runtimeBackend, concurrency, nil)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 		for _, imageURL := range images {
 			start := time.Now()
-			pullCmd := fmt.Sprintf("docker pull %s", imageURL)
-			_, err := runCommand(pullCmd)
+			_, err := puller.Pull(imageURL)
 			elapsed := time.Since(start)
 			registry := parseRegistry(imageURL)
 			res := CompareResult{
@@ -150,8 +206,12 @@ var ciCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		imageURL := args[0]
 		start := time.Now()
-		pullCmd := fmt.Sprintf("docker pull %s", imageURL)
-		_, err := runCommand(pullCmd)
+		puller, err := newPuller(runtimeBackend, concurrency, nil)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		_, err = puller.Pull(imageURL)
 		elapsed := time.Since(start)
 		result := struct {
 			Image     string `json:"image"`
@@ -160,16 +220,24 @@ var ciCmd = &cobra.Command{
 			PullTime  int64  `json:"pull_time_ms"`
 			Error     string `json:"error,omitempty"`
 			Timestamp string `json:"timestamp"`
+			Backend   string `json:"backend"`
 		}{
 			Image:     imageURL,
 			Registry:  parseRegistry(imageURL),
 			Success:   err == nil,
 			PullTime:  elapsed.Milliseconds(),
 			Timestamp: time.Now().Format(time.RFC3339),
+			Backend:   runtimeBackend,
 		}
 		if err != nil {
 			result.Error = err.Error()
 		}
+		if gatewayURL, _ := cmd.Flags().GetString("prometheus"); gatewayURL != "" {
+			ciResult := Result{Image: result.Image, Registry: result.Registry, Success: result.Success, PullTimeMs: result.PullTime, Error: result.Error}
+			if err := pushToGateway(gatewayURL, "pulltime_ci", "", formatMetrics([]Result{ciResult})); err != nil {
+				fmt.Printf("Error pushing to Prometheus gateway: %v\n", err)
+			}
+		}
 		jsonOut, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
 			fmt.Printf("Error generating JSON: %v\n", err)
@@ -189,6 +257,20 @@ var ciCmd = &cobra.Command{
 	},
 }
 
+// warmupIteration is one round of warmup's pull/remove loop, reporting the
+// cache state observed before the pull and the exact cached-vs-transferred
+// byte split, rather than assuming the first iteration is always cold.
+type warmupIteration struct {
+	Iteration        int    `json:"iteration"`
+	PullTimeMs       int64  `json:"pull_time_ms"`
+	CacheState       string `json:"cache_state"` // cached, partial, absent
+	CachedBytes      int64  `json:"cached_bytes"`
+	TransferredBytes int64  `json:"transferred_bytes"`
+	Success          bool   `json:"success"`
+	Error            string `json:"error,omitempty"`
+	Backend          string `json:"backend"`
+}
+
 var warmupCmd = &cobra.Command{
 	Use:   "warmup [IMAGE_URL]",
 	Short: "Repeatedly pull and remove an image to measure cold and warm cache pull times",
@@ -197,44 +279,60 @@ var warmupCmd = &cobra.Command{
 		imageURL := args[0]
 		iterations, _ := cmd.Flags().GetInt("iterations")
 		delay, _ := cmd.Flags().GetInt("delay")
-		var results []struct {
-			Iteration  int    `json:"iteration"`
-			PullTimeMs int64  `json:"pull_time_ms"`
-			CacheState string `json:"cache_state"`
-			Success    bool   `json:"success"`
-			Error      string `json:"error,omitempty"`
+		coldMode, _ := cmd.Flags().GetString("cold-mode")
+		switch coldMode {
+		case "none", "rmi", "prune", "drop-caches":
+		default:
+			fmt.Printf("Error: unknown --cold-mode %q (want none, rmi, prune, or drop-caches)\n", coldMode)
+			os.Exit(1)
+		}
+		if runtimeBackend != "" && runtimeBackend != "native" && runtimeBackend != "docker" {
+			fmt.Printf("Error: warmup only supports --runtime=native or --runtime=docker; cache-state tracking and cold-reset for %q aren't wired up, so results would be meaningless\n", runtimeBackend)
+			os.Exit(1)
 		}
+		puller, err := newPuller(runtimeBackend, concurrency, nil)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		var results []warmupIteration
 		for i := 1; i <= iterations; i++ {
-			// Remove image before cold pull
 			if i == 1 {
-				exec.Command("bash", "-c", fmt.Sprintf("docker rmi -f %s", imageURL)).Run()
+				coldModeReset(imageURL, coldMode)
 			}
+			state, stateErr := classifyCacheState(imageURL)
+
 			start := time.Now()
-			_, err := runCommand(fmt.Sprintf("docker pull %s", imageURL))
+			pullResult, err := puller.Pull(imageURL)
 			elapsed := time.Since(start)
-			cacheState := "cold"
-			if i > 1 {
-				cacheState = "warm"
+
+			var cachedBytes, transferredBytes int64
+			for _, lt := range pullResult.Layers {
+				if lt.Cached {
+					cachedBytes += lt.Size
+				} else {
+					transferredBytes += lt.BytesFetched
+				}
 			}
-			res := struct {
-				Iteration  int    `json:"iteration"`
-				PullTimeMs int64  `json:"pull_time_ms"`
-				CacheState string `json:"cache_state"`
-				Success    bool   `json:"success"`
-				Error      string `json:"error,omitempty"`
-			}{
-				Iteration:  i,
-				PullTimeMs: elapsed.Milliseconds(),
-				CacheState: cacheState,
-				Success:    err == nil,
+
+			res := warmupIteration{
+				Iteration:        i,
+				PullTimeMs:       elapsed.Milliseconds(),
+				CacheState:       state.State,
+				CachedBytes:      cachedBytes,
+				TransferredBytes: transferredBytes,
+				Success:          err == nil,
+				Backend:          runtimeBackend,
+			}
+			if stateErr != nil && err == nil {
+				err = stateErr
 			}
 			if err != nil {
 				res.Error = err.Error()
 			}
 			results = append(results, res)
 			if i < iterations {
-				// Remove image for next run
-				exec.Command("bash", "-c", fmt.Sprintf("docker rmi -f %s", imageURL)).Run()
+				coldModeReset(imageURL, coldMode)
 				time.Sleep(time.Duration(delay) * time.Millisecond)
 			}
 		}
@@ -248,22 +346,18 @@ var warmupCmd = &cobra.Command{
 }
 
 type Result struct {
-	Image      string `json:"image"`
-	Registry   string `json:"registry"`
-	Success    bool   `json:"success"`
-	PullTimeMs int64  `json:"pull_time_ms"`
-	StartTime  string `json:"start_time"`
-	EndTime    string `json:"end_time"`
-	Error      string `json:"error,omitempty"`
-	Bytes      int64  `json:"bytes_downloaded,omitempty"`
-	Layers     int    `json:"layers,omitempty"`
-	CmdOutput  string `json:"cmd_output,omitempty"`
-}
-
-func runCommand(cmd string) (string, error) {
-	c := exec.Command("bash", "-c", cmd)
-	output, err := c.CombinedOutput()
-	return string(output), err
+	Image         string         `json:"image"`
+	Registry      string         `json:"registry"`
+	Success       bool           `json:"success"`
+	PullTimeMs    int64          `json:"pull_time_ms"`
+	StartTime     string         `json:"start_time"`
+	EndTime       string         `json:"end_time"`
+	Error         string         `json:"error,omitempty"`
+	Bytes         int64          `json:"bytes_downloaded,omitempty"`
+	Layers        int            `json:"layers,omitempty"`
+	LayerTimings  []LayerTiming  `json:"layer_timings,omitempty"`
+	MirrorResults []MirrorResult `json:"mirror_results,omitempty"`
+	Backend       string         `json:"backend,omitempty"`
 }
 
 func parseRegistry(image string) string {
@@ -286,58 +380,62 @@ func isOfficialDockerHub(image string) bool {
 	return indexOf(image, '.') == -1 && indexOf(image, ':') == -1
 }
 
-func parseDockerOutput(res *Result, output string) {
-	var layers, bytes int64
-	for _, line := range splitLines(output) {
-		if n, _ := fmt.Sscanf(line, "Downloaded newer image for %*s"); n > 0 {
-			continue
-		}
-		if n, _ := fmt.Sscanf(line, "%dB", &bytes); n == 1 {
-			res.Bytes = bytes
-		}
-		if n, _ := fmt.Sscanf(line, "Pulling fs layer"); n > 0 {
-			layers++
-		}
-	}
-	if layers > 0 {
-		res.Layers = int(layers)
-	}
-}
-
-func splitLines(s string) []string {
-	return strings.Split(s, "\n")
-}
-
 func printSummary(results []Result) {
 	total := len(results)
 	success := 0
-	var min, max, sum int64
-	for i, r := range results {
+	var min, max, sum, bytes int64
+	seenSuccess := false
+	for _, r := range results {
 		if r.Success {
 			success++
-			if i == 0 || r.PullTimeMs < min {
+			if !seenSuccess || r.PullTimeMs < min {
 				min = r.PullTimeMs
 			}
+			seenSuccess = true
 			if r.PullTimeMs > max {
 				max = r.PullTimeMs
 			}
 			sum += r.PullTimeMs
+			bytes += r.Bytes
 		}
 	}
-	fmt.Printf("\nSummary: %d/%d succeeded | min: %dms | max: %dms | avg: %.2fms\n", success, total, min, max, float64(sum)/float64(success))
+	avg := time.Duration(0)
+	if success > 0 {
+		avg = time.Duration(sum/int64(success)) * time.Millisecond
+	}
+	fmt.Printf("\nSummary: %d/%d succeeded | min: %s | max: %s | avg: %s | total: %s\n",
+		success, total, humanDuration(time.Duration(min)*time.Millisecond), humanDuration(time.Duration(max)*time.Millisecond), humanDuration(avg), humanBytes(bytes))
+
+	if repeatCount > 1 {
+		perImage, global := computeAggregates(results)
+		fmt.Printf("Aggregate over %d run(s) per image:\n", repeatCount)
+		for _, image := range sortedImageKeys(perImage) {
+			agg := perImage[image]
+			fmt.Printf("  %s: p50=%s p90=%s p99=%s mean=%s stddev=%s\n",
+				image, humanDuration(time.Duration(agg.P50Ms)*time.Millisecond), humanDuration(time.Duration(agg.P90Ms)*time.Millisecond),
+				humanDuration(time.Duration(agg.P99Ms)*time.Millisecond), humanDuration(time.Duration(agg.MeanMs)*time.Millisecond), humanDuration(time.Duration(agg.StdDevMs)*time.Millisecond))
+		}
+		fmt.Printf("  global: p50=%s p90=%s p99=%s\n", humanDuration(time.Duration(global.P50Ms)*time.Millisecond), humanDuration(time.Duration(global.P90Ms)*time.Millisecond), humanDuration(time.Duration(global.P99Ms)*time.Millisecond))
+	}
 }
 
 func init() {
 	benchmarkCmd.Flags().IntVarP(&concurrency, "concurrent", "c", 2, "Number of concurrent pulls")
 	benchmarkCmd.Flags().IntVarP(&timeoutSec, "timeout", "t", 120, "Timeout (seconds) for each pull")
 	benchmarkCmd.Flags().BoolVarP(&outputSummary, "summary", "s", false, "Print summary statistics")
+	benchmarkCmd.Flags().IntVar(&repeatCount, "repeat", 1, "Number of measured pulls per image")
+	benchmarkCmd.Flags().IntVar(&warmupCount, "warmup", 0, "Number of discarded warmup pulls per image before measuring")
 	rootCmd.AddCommand(imageCmd)
 	rootCmd.AddCommand(benchmarkCmd)
 	rootCmd.AddCommand(compareCmd)
 	rootCmd.AddCommand(ciCmd)
 	warmupCmd.Flags().IntP("iterations", "n", 3, "Number of pull/remove iterations")
 	warmupCmd.Flags().IntP("delay", "d", 1000, "Delay (ms) between iterations")
+	warmupCmd.Flags().String("cold-mode", "rmi", "How to force a cold pull between iterations: none (leave the cache alone, to observe natural warm reuse), rmi, prune, or drop-caches")
 	rootCmd.AddCommand(warmupCmd)
+}
+
+func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// metricGroup accumulates every measurement for one image/registry/mirror
+// combination across a --repeat run, so formatMetrics can emit a single
+// aggregated label set instead of one line per run.
+type metricGroup struct {
+	image, registry, mirror string
+	ms                      []int64
+	bytes                   int64
+	layers                  int
+	runs, successCount      int
+}
+
+func (g *metricGroup) add(success bool, pullTimeMs int64, bytes int64, layers int) {
+	g.runs++
+	if success {
+		g.successCount++
+		g.ms = append(g.ms, pullTimeMs)
+	}
+	g.bytes += bytes
+	if layers > 0 {
+		g.layers = layers
+	}
+}
+
+// groupMetrics folds results (and their nested mirror results) into one
+// metricGroup per image/registry/mirror combination, merging repeated runs
+// under --repeat into the same group.
+func groupMetrics(results []Result) []*metricGroup {
+	index := map[string]*metricGroup{}
+	var order []*metricGroup
+
+	get := func(image, registry, mirror string) *metricGroup {
+		key := image + "|" + registry + "|" + mirror
+		g, ok := index[key]
+		if !ok {
+			g = &metricGroup{image: image, registry: registry, mirror: mirror}
+			index[key] = g
+			order = append(order, g)
+		}
+		return g
+	}
+
+	for _, r := range results {
+		get(r.Image, r.Registry, "").add(r.Success, r.PullTimeMs, r.Bytes, r.Layers)
+		for _, mr := range r.MirrorResults {
+			get(r.Image, r.Registry, mr.Endpoint).add(mr.Success, mr.PullTimeMs, mr.Bytes, mr.Layers)
+		}
+	}
+	return order
+}
+
+// formatMetrics renders results as Prometheus/OpenMetrics text exposition
+// format: one gauge per image/mirror for pull seconds (as a quantile
+// summary), bytes downloaded, layer count, and success rate, matching the
+// shape CI scrapers/pushgateway expect. Results are aggregated per
+// image/registry/mirror first, since a --repeat run produces several
+// Results with identical labels and Prometheus rejects duplicate label
+// sets within one exposition payload.
+func formatMetrics(results []Result) string {
+	var b strings.Builder
+	groups := groupMetrics(results)
+
+	b.WriteString("# HELP pulltime_pull_seconds Time taken to pull an image, in seconds, by quantile across repeated runs.\n")
+	b.WriteString("# TYPE pulltime_pull_seconds summary\n")
+	for _, g := range groups {
+		agg := summarize(g.ms, g.runs, g.successCount, g.bytes)
+		for _, q := range []struct {
+			label string
+			ms    int64
+		}{{"0.5", agg.P50Ms}, {"0.9", agg.P90Ms}, {"0.99", agg.P99Ms}} {
+			fmt.Fprintf(&b, "pulltime_pull_seconds{image=%q,registry=%q,mirror=%q,quantile=%q} %f\n", g.image, g.registry, g.mirror, q.label, float64(q.ms)/1000)
+		}
+	}
+
+	b.WriteString("# HELP pulltime_bytes_downloaded_total Bytes downloaded while pulling an image, summed across repeated runs.\n")
+	b.WriteString("# TYPE pulltime_bytes_downloaded_total counter\n")
+	for _, g := range groups {
+		fmt.Fprintf(&b, "pulltime_bytes_downloaded_total{image=%q,registry=%q,mirror=%q} %d\n", g.image, g.registry, g.mirror, g.bytes)
+	}
+
+	b.WriteString("# HELP pulltime_layers Number of layers in the pulled image.\n")
+	b.WriteString("# TYPE pulltime_layers gauge\n")
+	for _, g := range groups {
+		fmt.Fprintf(&b, "pulltime_layers{image=%q,registry=%q,mirror=%q} %d\n", g.image, g.registry, g.mirror, g.layers)
+	}
+
+	b.WriteString("# HELP pulltime_success_ratio Fraction of runs that succeeded, from 0 to 1.\n")
+	b.WriteString("# TYPE pulltime_success_ratio gauge\n")
+	for _, g := range groups {
+		ratio := 0.0
+		if g.runs > 0 {
+			ratio = float64(g.successCount) / float64(g.runs)
+		}
+		fmt.Fprintf(&b, "pulltime_success_ratio{image=%q,registry=%q,mirror=%q} %f\n", g.image, g.registry, g.mirror, ratio)
+	}
+
+	return b.String()
+}
+
+// pushToGateway pushes body (already-formatted exposition text) to a
+// Prometheus Pushgateway under the given job/instance labels, replacing any
+// prior push for the same grouping key (PUT semantics).
+func pushToGateway(gatewayURL, job, instance string, body string) error {
+	url := fmt.Sprintf("%s/metrics/job/%s", strings.TrimRight(gatewayURL, "/"), job)
+	if instance != "" {
+		url += "/instance/" + instance
+	}
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pushgateway returned %s", resp.Status)
+	}
+	return nil
+}
+
+// loadResults reads a JSON file produced by `benchmark` or `ci`, accepting
+// either a bare []Result array or a {"results": [...]} report object.
+func loadResults(path string) ([]Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	if err := json.Unmarshal(data, &results); err == nil {
+		return results, nil
+	}
+	var report struct {
+		Results []Result `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("unrecognized results JSON: %w", err)
+	}
+	return report.Results, nil
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export [RESULTS_JSON_FILE]",
+	Short: "Convert a benchmark/ci JSON report into Prometheus exposition format",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		results, err := loadResults(args[0])
+		if err != nil {
+			fmt.Printf("Error loading results: %v\n", err)
+			os.Exit(1)
+		}
+		body := formatMetrics(results)
+
+		outputFile, _ := cmd.Flags().GetString("output")
+		listenAddr, _ := cmd.Flags().GetString("listen")
+		gatewayURL, _ := cmd.Flags().GetString("pushgateway")
+		job, _ := cmd.Flags().GetString("job")
+		instance, _ := cmd.Flags().GetString("instance")
+
+		switch {
+		case gatewayURL != "":
+			if err := pushToGateway(gatewayURL, job, instance, body); err != nil {
+				fmt.Printf("Error pushing to gateway: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Pushed metrics for job=%s to %s\n", job, gatewayURL)
+		case listenAddr != "":
+			fmt.Printf("Serving metrics on %s/metrics\n", listenAddr)
+			http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body))
+			})
+			if err := http.ListenAndServe(listenAddr, nil); err != nil {
+				fmt.Printf("Error serving metrics: %v\n", err)
+				os.Exit(1)
+			}
+		case outputFile != "":
+			if err := os.WriteFile(outputFile, []byte(body), 0644); err != nil {
+				fmt.Printf("Error writing metrics file: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Metrics written to %s\n", outputFile)
+		default:
+			fmt.Print(body)
+		}
+	},
+}
+
+func init() {
+	exportCmd.Flags().String("output", "", "Write metrics in Prometheus text format to this file")
+	exportCmd.Flags().String("listen", "", "Serve metrics on this address (e.g. :9090) instead of exiting")
+	exportCmd.Flags().String("pushgateway", "", "Push metrics to this Prometheus Pushgateway URL instead of printing")
+	exportCmd.Flags().String("job", "pulltime", "Job label to use when pushing to a Pushgateway")
+	exportCmd.Flags().String("instance", "", "Instance label to use when pushing to a Pushgateway")
+	rootCmd.AddCommand(exportCmd)
+
+	benchmarkCmd.Flags().String("prometheus", "", "Pushgateway URL to push benchmark metrics to after the run")
+	ciCmd.Flags().String("prometheus", "", "Pushgateway URL to push the ci result metrics to after the run")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// MirrorResult is the per-endpoint outcome of pulling the same image through
+// a mirror (or the canonical registry) via the native puller.
+type MirrorResult struct {
+	Endpoint   string `json:"endpoint"`
+	Origin     bool   `json:"origin"`
+	Success    bool   `json:"success"`
+	PullTimeMs int64  `json:"pull_time_ms"`
+	TTFBMs     int64  `json:"ttfb_ms"`
+	Bytes      int64  `json:"bytes_downloaded"`
+	Layers     int    `json:"layers"`
+	Error      string `json:"error,omitempty"`
+}
+
+// resolveCanonicalDigest resolves the digest canonical's reference points at,
+// preferring the canonical registry itself but falling back to each of
+// hosts in order if canonical is unreachable, so a mirror comparison can
+// still proceed when only the canonical registry is having trouble.
+func resolveCanonicalDigest(canonical imageRef, hosts []string) (string, error) {
+	attempts := append([]string{canonical.Registry}, hosts...)
+	var lastErr error
+	for _, host := range attempts {
+		ref := canonical
+		ref.Registry = host
+		client := newRegistryClient(resolveCredential(host))
+		digest, err := client.resolveDigest(ref)
+		if err == nil {
+			return digest, nil
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+// pullThroughEndpoints resolves imageURL's manifest digest once (preferring
+// the canonical registry, falling back to a mirror if canonical is
+// unreachable), then pulls that exact digest once per endpoint in endpoints
+// (each substituted in as the registry host) plus once more through the
+// canonical registry itself, and returns a MirrorResult for each attempt in
+// that order. Pulling the resolved digest rather than the original tag
+// reference ensures every endpoint is compared against the same content,
+// even if a mirror's tag has moved relative to canonical.
+func pullThroughEndpoints(imageURL string, endpoints []string, maxConcurrency int) []MirrorResult {
+	canonical := parseImageRef(imageURL)
+	hosts := append([]string{}, endpoints...)
+	hosts = append(hosts, canonical.Registry)
+
+	digest, err := resolveCanonicalDigest(canonical, endpoints)
+	if err != nil {
+		results := make([]MirrorResult, 0, len(hosts))
+		for i, host := range hosts {
+			results = append(results, MirrorResult{
+				Endpoint: host,
+				Origin:   i == len(hosts)-1,
+				Error:    fmt.Sprintf("resolving digest: %v", err),
+			})
+		}
+		return results
+	}
+
+	results := make([]MirrorResult, 0, len(hosts))
+	for i, host := range hosts {
+		ref := canonical
+		ref.Registry = host
+		ref.Reference = digest
+		start := time.Now()
+		pullResult, ttfb, err := pullRef(ref, maxConcurrency, nil)
+		elapsed := time.Since(start)
+		res := MirrorResult{
+			Endpoint:   host,
+			Origin:     i == len(hosts)-1,
+			Success:    err == nil,
+			PullTimeMs: elapsed.Milliseconds(),
+			TTFBMs:     ttfb.Milliseconds(),
+			Bytes:      pullResult.TotalBytes,
+			Layers:     len(pullResult.Layers),
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+var mirrorsCmd = &cobra.Command{
+	Use:   "mirrors [IMAGE_URL]",
+	Short: "Pull an image through each mirror endpoint and compare with the canonical registry",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		imageURL := args[0]
+		endpoints, _ := cmd.Flags().GetStringArray("mirror")
+		results := pullThroughEndpoints(imageURL, endpoints, concurrency)
+		jsonOut, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fmt.Printf("Error generating JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(jsonOut))
+	},
+}
+
+func init() {
+	mirrorsCmd.Flags().StringArray("mirror", nil, "Mirror registry host to try before the canonical registry (repeatable)")
+	benchmarkCmd.Flags().StringArray("mirror", nil, "Mirror registry host to benchmark alongside the canonical registry (repeatable)")
+	rootCmd.AddCommand(mirrorsCmd)
+}
@@ -0,0 +1,173 @@
+// Package auth resolves registry credentials the same way the Docker CLI
+// does: reading ~/.docker/config.json (honoring DOCKER_CONFIG), shelling
+// out to docker-credential-* helpers, and caching bearer tokens per scope
+// for the lifetime of a single run.
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Credential is a resolved username/password (or identity token) pair for a
+// single registry.
+type Credential struct {
+	Username string
+	Password string
+}
+
+// Empty reports whether c carries no credential at all.
+func (c Credential) Empty() bool {
+	return c.Username == "" && c.Password == ""
+}
+
+type configFile struct {
+	Auths map[string]struct {
+		Auth string `json:"auth"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// Store resolves credentials for a registry host from a loaded docker
+// config file, falling back to anonymous access when nothing matches.
+type Store struct {
+	cfg configFile
+}
+
+// ConfigPath resolves the docker config.json path the same way the Docker
+// CLI does: an explicit override, then $DOCKER_CONFIG/config.json, then
+// ~/.docker/config.json.
+func ConfigPath(override string) string {
+	if override != "" {
+		return override
+	}
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// LoadStore reads and parses the docker config.json at path. A missing file
+// is not an error: it simply yields a Store with no credentials, so callers
+// fall back to anonymous access.
+func LoadStore(path string) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Store{}, nil
+		}
+		return nil, err
+	}
+	var cfg configFile
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &Store{cfg: cfg}, nil
+}
+
+// normalizeRegistry maps the Docker Hub registry host used by the registry
+// client to the key Docker config files store Hub credentials under.
+func normalizeRegistry(registry string) string {
+	if registry == "registry-1.docker.io" || registry == "docker.io" {
+		return "https://index.docker.io/v1/"
+	}
+	return registry
+}
+
+// CredentialFor resolves a credential for registry, trying a per-registry
+// credential helper, the global credsStore, and finally the inline auths
+// entry, in that order. The second return value is false when no
+// credential was found, in which case the caller should fall back to an
+// anonymous request.
+func (s *Store) CredentialFor(registry string) (Credential, bool, error) {
+	if s == nil {
+		return Credential{}, false, nil
+	}
+	key := normalizeRegistry(registry)
+
+	if helper, ok := s.cfg.CredHelpers[key]; ok {
+		cred, err := credentialHelperGet(helper, key)
+		if err != nil {
+			return Credential{}, false, err
+		}
+		return cred, !cred.Empty(), nil
+	}
+	if s.cfg.CredsStore != "" {
+		cred, err := credentialHelperGet(s.cfg.CredsStore, key)
+		if err == nil && !cred.Empty() {
+			return cred, true, nil
+		}
+	}
+	if entry, ok := s.cfg.Auths[key]; ok && entry.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return Credential{}, false, fmt.Errorf("decoding auth for %s: %w", key, err)
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) == 2 {
+			return Credential{Username: parts[0], Password: parts[1]}, true, nil
+		}
+	}
+	return Credential{}, false, nil
+}
+
+// credentialHelperGet execs `docker-credential-<helper> get`, writing the
+// registry host to stdin and parsing the {ServerURL,Username,Secret} JSON
+// response the helper protocol requires.
+func credentialHelperGet(helper, registry string) (Credential, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(registry)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return Credential{}, fmt.Errorf("docker-credential-%s get: %w", helper, err)
+	}
+	var resp struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return Credential{}, fmt.Errorf("parsing docker-credential-%s output: %w", helper, err)
+	}
+	return Credential{Username: resp.Username, Password: resp.Secret}, nil
+}
+
+// TokenCache caches bearer tokens by scope for the duration of a single
+// run, so a benchmark pulling many layers from the same registry/repo only
+// performs the WWW-Authenticate challenge once.
+type TokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// NewTokenCache returns an empty TokenCache.
+func NewTokenCache() *TokenCache {
+	return &TokenCache{tokens: map[string]string{}}
+}
+
+// Get returns the cached token for key, if any.
+func (tc *TokenCache) Get(key string) (string, bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tok, ok := tc.tokens[key]
+	return tok, ok
+}
+
+// Set stores token under key for later Get calls.
+func (tc *TokenCache) Set(key, token string) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.tokens[key] = token
+}
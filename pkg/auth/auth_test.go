@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, cfg configFile) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("marshaling test config: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("writing test config: %v", err)
+	}
+	return path
+}
+
+func TestCredentialForInlineAuth(t *testing.T) {
+	cfg := configFile{Auths: map[string]struct {
+		Auth string `json:"auth"`
+	}{
+		"ghcr.io": {Auth: "dXNlcjpwYXNz"}, // base64("user:pass")
+	}}
+	store, err := LoadStore(writeConfig(t, cfg))
+	if err != nil {
+		t.Fatalf("LoadStore: %v", err)
+	}
+
+	cred, ok, err := store.CredentialFor("ghcr.io")
+	if err != nil {
+		t.Fatalf("CredentialFor: %v", err)
+	}
+	if !ok {
+		t.Fatal("CredentialFor reported no credential found")
+	}
+	if cred.Username != "user" || cred.Password != "pass" {
+		t.Errorf("CredentialFor = %+v, want user/pass", cred)
+	}
+}
+
+func TestCredentialForNormalizesDockerHub(t *testing.T) {
+	cfg := configFile{Auths: map[string]struct {
+		Auth string `json:"auth"`
+	}{
+		"https://index.docker.io/v1/": {Auth: "dXNlcjpwYXNz"},
+	}}
+	store, err := LoadStore(writeConfig(t, cfg))
+	if err != nil {
+		t.Fatalf("LoadStore: %v", err)
+	}
+
+	_, ok, err := store.CredentialFor("registry-1.docker.io")
+	if err != nil {
+		t.Fatalf("CredentialFor: %v", err)
+	}
+	if !ok {
+		t.Error("CredentialFor should resolve registry-1.docker.io against the index.docker.io/v1/ auths key")
+	}
+}
+
+func TestCredentialForNoMatch(t *testing.T) {
+	store, err := LoadStore(writeConfig(t, configFile{}))
+	if err != nil {
+		t.Fatalf("LoadStore: %v", err)
+	}
+	_, ok, err := store.CredentialFor("ghcr.io")
+	if err != nil {
+		t.Fatalf("CredentialFor: %v", err)
+	}
+	if ok {
+		t.Error("CredentialFor should report no credential for an empty config")
+	}
+}
+
+func TestCredentialForNilStore(t *testing.T) {
+	var store *Store
+	cred, ok, err := store.CredentialFor("ghcr.io")
+	if err != nil || ok || !cred.Empty() {
+		t.Errorf("CredentialFor on a nil store should report (empty, false, nil), got (%+v, %v, %v)", cred, ok, err)
+	}
+}
+
+func TestLoadStoreMissingFile(t *testing.T) {
+	store, err := LoadStore(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadStore should not error on a missing file, got: %v", err)
+	}
+	if _, ok, _ := store.CredentialFor("ghcr.io"); ok {
+		t.Error("a Store from a missing config file should never resolve a credential")
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	containerd "github.com/containerd/containerd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	criapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/spf13/cobra"
+)
+
+// Puller abstracts over the different ways an image can be pulled, so
+// benchmark commands can compare the exact code path Kubernetes (CRI),
+// containerd, or a Docker daemon will hit in production instead of
+// assuming Docker semantics.
+type Puller interface {
+	// Name identifies the backend for the Result.Backend field.
+	Name() string
+	// Pull fetches imageURL and returns whatever timing/byte detail the
+	// backend is able to report; backends that shell out to another CLI
+	// can only report success/failure, not per-layer detail.
+	Pull(imageURL string) (nativePullResult, error)
+}
+
+// DockerPuller shells out to the docker CLI, the tool's original behavior.
+type DockerPuller struct{}
+
+func (DockerPuller) Name() string { return "docker" }
+
+func (DockerPuller) Pull(imageURL string) (nativePullResult, error) {
+	cmd := exec.Command("bash", "-c", fmt.Sprintf("docker pull %s", imageURL))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nativePullResult{}, fmt.Errorf("docker pull: %w: %s", err, string(output))
+	}
+	return nativePullResult{}, nil
+}
+
+// ContainerdPuller pulls an image through containerd's Go client against a
+// configurable containerd socket, so the measured path matches what a
+// kubelet using containerd directly would hit.
+type ContainerdPuller struct {
+	Socket    string
+	Namespace string
+}
+
+func (ContainerdPuller) Name() string { return "containerd" }
+
+func (p ContainerdPuller) Pull(imageURL string) (nativePullResult, error) {
+	socket := p.Socket
+	if socket == "" {
+		socket = "/run/containerd/containerd.sock"
+	}
+	ns := p.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+
+	client, err := containerd.New(socket, containerd.WithDefaultNamespace(ns))
+	if err != nil {
+		return nativePullResult{}, fmt.Errorf("connecting to containerd at %s: %w", socket, err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	img, err := client.Pull(ctx, imageURL, containerd.WithPullUnpack)
+	if err != nil {
+		return nativePullResult{}, fmt.Errorf("containerd pull: %w", err)
+	}
+
+	size, err := img.Size(ctx)
+	if err != nil {
+		return nativePullResult{}, fmt.Errorf("measuring pulled image size: %w", err)
+	}
+	diffIDs, err := img.RootFS(ctx)
+	if err != nil {
+		return nativePullResult{}, fmt.Errorf("reading image rootfs: %w", err)
+	}
+	return nativePullResult{TotalBytes: size, LayerCount: len(diffIDs)}, nil
+}
+
+// CRIPuller pulls an image through the Kubernetes CRI PullImage RPC against
+// a configurable runtime endpoint (containerd's CRI plugin, CRI-O, etc), the
+// same call path a kubelet makes when scheduling a pod.
+type CRIPuller struct {
+	Endpoint string
+}
+
+func (CRIPuller) Name() string { return "cri" }
+
+func (p CRIPuller) Pull(imageURL string) (nativePullResult, error) {
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = "unix:///run/containerd/containerd.sock"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		return nativePullResult{}, fmt.Errorf("dialing CRI endpoint %s: %w", endpoint, err)
+	}
+	defer conn.Close()
+
+	client := criapi.NewImageServiceClient(conn)
+	spec := &criapi.ImageSpec{Image: imageURL}
+
+	if _, err := client.PullImage(ctx, &criapi.PullImageRequest{Image: spec}); err != nil {
+		return nativePullResult{}, fmt.Errorf("CRI PullImage: %w", err)
+	}
+
+	status, err := client.ImageStatus(ctx, &criapi.ImageStatusRequest{Image: spec})
+	if err != nil || status.Image == nil {
+		return nativePullResult{}, nil
+	}
+	return nativePullResult{TotalBytes: int64(status.Image.Size_)}, nil
+}
+
+// NativePuller pulls directly against the registry's v2 HTTP API with no
+// runtime dependency, via pullImageNative.
+type NativePuller struct {
+	MaxConcurrency int
+	OnProgress     func(ProgressEvent)
+}
+
+func (NativePuller) Name() string { return "native" }
+
+func (p NativePuller) Pull(imageURL string) (nativePullResult, error) {
+	return pullImageNative(imageURL, p.MaxConcurrency, p.OnProgress)
+}
+
+// newPuller constructs the Puller for the given --runtime value, defaulting
+// to the native registry client.
+func newPuller(runtime string, maxConcurrency int, onProgress func(ProgressEvent)) (Puller, error) {
+	switch runtime {
+	case "", "native":
+		return NativePuller{MaxConcurrency: maxConcurrency, OnProgress: onProgress}, nil
+	case "docker":
+		return DockerPuller{}, nil
+	case "containerd":
+		return ContainerdPuller{Socket: containerdSocket, Namespace: containerdNamespace}, nil
+	case "cri":
+		return CRIPuller{Endpoint: criEndpoint}, nil
+	default:
+		return nil, fmt.Errorf("unknown runtime %q (want docker, containerd, cri, or native)", runtime)
+	}
+}
+
+var (
+	runtimeBackend      string
+	containerdSocket    string
+	containerdNamespace string
+	criEndpoint         string
+)
+
+// addRuntimeFlags registers the shared --runtime flag (and its
+// backend-specific socket/endpoint flags) on a pull-issuing command.
+func addRuntimeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&runtimeBackend, "runtime", "native", "Pull backend to use: docker, containerd, cri, or native")
+	cmd.Flags().StringVar(&containerdSocket, "containerd-socket", "/run/containerd/containerd.sock", "containerd socket path, used with --runtime=containerd")
+	cmd.Flags().StringVar(&containerdNamespace, "containerd-namespace", "default", "containerd namespace, used with --runtime=containerd")
+	cmd.Flags().StringVar(&criEndpoint, "cri-endpoint", "unix:///run/containerd/containerd.sock", "CRI runtime endpoint, used with --runtime=cri")
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{imageCmd, benchmarkCmd, compareCmd, ciCmd, warmupCmd} {
+		addRuntimeFlags(cmd)
+	}
+}
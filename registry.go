@@ -0,0 +1,496 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vaidikcode/Pull-Time/pkg/auth"
+)
+
+// imageRef is a parsed "registry/repository:reference" image string.
+type imageRef struct {
+	Registry   string
+	Repository string
+	Reference  string // tag or digest
+}
+
+// parseImageRef splits an image string into its registry, repository and
+// reference components, defaulting to Docker Hub and the "latest" tag the
+// same way the Docker CLI does.
+func parseImageRef(image string) imageRef {
+	ref := imageRef{Registry: "registry-1.docker.io", Reference: "latest"}
+
+	name := image
+	if idx := strings.Index(name, "@"); idx != -1 {
+		ref.Reference = name[idx+1:]
+		name = name[:idx]
+	} else if idx := strings.LastIndex(name, ":"); idx != -1 && !strings.Contains(name[idx:], "/") {
+		ref.Reference = name[idx+1:]
+		name = name[:idx]
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.Contains(parts[0], ".") || strings.Contains(parts[0], ":") || parts[0] == "localhost") {
+		ref.Registry = parts[0]
+		ref.Repository = parts[1]
+	} else {
+		ref.Repository = name
+		if !strings.Contains(ref.Repository, "/") {
+			ref.Repository = "library/" + ref.Repository
+		}
+	}
+	return ref
+}
+
+// LayerTiming captures how long a single blob took to fetch and how large it
+// was, so callers can see where pull time is actually spent.
+type LayerTiming struct {
+	Digest        string  `json:"digest"`
+	Size          int64   `json:"size"`
+	BytesFetched  int64   `json:"bytes_fetched"`
+	ElapsedMs     int64   `json:"elapsed_ms"`
+	ThroughputMBs float64 `json:"throughput_mbs"`
+	Cached        bool    `json:"cached"`
+}
+
+// ProgressEvent is one line of the streaming JSON progress output emitted
+// while pulling, modeled after Docker's jsonmessage stream.
+type ProgressEvent struct {
+	Layer     string `json:"layer"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Bytes     int64  `json:"bytes"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+	State     string `json:"state"` // starting, downloading, cached, done, error
+}
+
+type manifestLayer struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type manifestV2 struct {
+	MediaType string          `json:"mediaType"`
+	Config    manifestLayer   `json:"config"`
+	Layers    []manifestLayer `json:"layers"`
+}
+
+// manifestListEntry is one platform-specific manifest referenced by a
+// manifest list / OCI image index.
+type manifestListEntry struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Platform  struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform"`
+}
+
+// manifestList is a Docker manifest list (or OCI image index): a fat
+// manifest pointing at one single-image manifest per platform. Most
+// multi-arch images on Docker Hub, GHCR, and Quay serve this for a tag
+// instead of a manifestV2 directly.
+type manifestList struct {
+	MediaType string              `json:"mediaType"`
+	Manifests []manifestListEntry `json:"manifests"`
+}
+
+const (
+	mediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeImageIndex   = "application/vnd.oci.image.index.v1+json"
+)
+
+// selectManifest picks the entry matching the local platform from a
+// manifest list, falling back to the first entry if nothing matches
+// (mirroring what `docker pull` does when amd64 isn't present, e.g. a
+// `windows` or `unknown` platform leaking into the index).
+func selectManifest(list manifestList) (manifestListEntry, error) {
+	if len(list.Manifests) == 0 {
+		return manifestListEntry{}, fmt.Errorf("manifest list has no entries")
+	}
+	for _, m := range list.Manifests {
+		if m.Platform.OS == runtime.GOOS && m.Platform.Architecture == runtime.GOARCH {
+			return m, nil
+		}
+	}
+	return list.Manifests[0], nil
+}
+
+// registryClient is a minimal Docker Registry v2 client: just enough to
+// resolve a manifest and fetch blobs, with no dependency on a running
+// Docker daemon.
+type registryClient struct {
+	http *http.Client
+	cred auth.Credential
+}
+
+// sharedTokenCache caches bearer tokens by scope across every registryClient
+// for the lifetime of the process, so a benchmark run only performs the
+// WWW-Authenticate challenge once per repository.
+var sharedTokenCache = auth.NewTokenCache()
+
+func newRegistryClient(cred auth.Credential) *registryClient {
+	return &registryClient{http: &http.Client{Timeout: 60 * time.Second}, cred: cred}
+}
+
+func (c *registryClient) baseURL(ref imageRef) string {
+	registry := ref.Registry
+	if registry == "docker.io" {
+		registry = "registry-1.docker.io"
+	}
+	return "https://" + registry
+}
+
+// authenticate performs the WWW-Authenticate/Bearer token dance for a single
+// request, returning an Authorization header value (possibly empty for
+// registries that don't require auth for the given scope).
+func (c *registryClient) authenticate(wwwAuth string) (string, error) {
+	params := parseAuthHeader(wwwAuth)
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("no realm in WWW-Authenticate header: %q", wwwAuth)
+	}
+
+	cacheKey := realm + "|" + params["service"] + "|" + params["scope"]
+	if tok, ok := sharedTokenCache.Get(cacheKey); ok {
+		return tok, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, realm, nil)
+	if err != nil {
+		return "", err
+	}
+	q := req.URL.Query()
+	for _, k := range []string{"service", "scope"} {
+		if v, ok := params[k]; ok {
+			q.Set(k, v)
+		}
+	}
+	req.URL.RawQuery = q.Encode()
+	if !c.cred.Empty() {
+		req.SetBasicAuth(c.cred.Username, c.cred.Password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed: %s", resp.Status)
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", err
+	}
+	token := tok.Token
+	if token == "" {
+		token = tok.AccessToken
+	}
+	sharedTokenCache.Set(cacheKey, token)
+	return token, nil
+}
+
+func parseAuthHeader(header string) map[string]string {
+	out := map[string]string{}
+	header = strings.TrimPrefix(header, "Bearer ")
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		out[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+	return out
+}
+
+// doAuthenticated issues req, transparently retrying once with a bearer
+// token if the registry challenges the request with a 401.
+func (c *registryClient) doAuthenticated(req *http.Request) (*http.Response, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	wwwAuth := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if wwwAuth == "" {
+		return nil, fmt.Errorf("unauthorized and no WWW-Authenticate challenge")
+	}
+	token, err := c.authenticate(wwwAuth)
+	if err != nil {
+		return nil, fmt.Errorf("authenticating: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return c.http.Do(req)
+}
+
+// manifest resolves the manifest for ref, following the v2 manifest schema.
+// If the registry serves a manifest list or OCI image index (the common case
+// for multi-arch images), it picks the platform-matching entry and recurses
+// into it so callers always get back a single-image manifest with Layers
+// populated.
+func (c *registryClient) manifest(ref imageRef) (manifestV2, error) {
+	m, _, err := c.manifestAndDigest(ref)
+	return m, err
+}
+
+// resolveDigest returns the digest of the single-image manifest ref points
+// at, resolving through any manifest list/image index along the way. Callers
+// that need to compare the exact same content across multiple registries
+// (e.g. mirrors) should resolve the digest once and pull that digest from
+// every endpoint, since a tag reference like "latest" can point at different
+// content per registry.
+func (c *registryClient) resolveDigest(ref imageRef) (string, error) {
+	_, digest, err := c.manifestAndDigest(ref)
+	return digest, err
+}
+
+// manifestAndDigest is the shared implementation behind manifest and
+// resolveDigest: it fetches ref's manifest, follows manifest lists/image
+// indexes to a single-image manifest, and returns that manifest alongside
+// its own digest (as opposed to ref.Reference, which may be a tag).
+func (c *registryClient) manifestAndDigest(ref imageRef) (manifestV2, string, error) {
+	url := fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL(ref), ref.Repository, ref.Reference)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return manifestV2{}, "", err
+	}
+	req.Header.Set("Accept", strings.Join([]string{
+		"application/vnd.docker.distribution.manifest.v2+json",
+		"application/vnd.oci.image.manifest.v1+json",
+		mediaTypeManifestList,
+		mediaTypeImageIndex,
+	}, ", "))
+	resp, err := c.doAuthenticated(req)
+	if err != nil {
+		return manifestV2{}, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return manifestV2{}, "", fmt.Errorf("manifest fetch failed: %s: %s", resp.Status, string(body))
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return manifestV2{}, "", err
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	}
+
+	var probe struct {
+		MediaType string `json:"mediaType"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return manifestV2{}, "", err
+	}
+
+	if probe.MediaType == mediaTypeManifestList || probe.MediaType == mediaTypeImageIndex {
+		var list manifestList
+		if err := json.Unmarshal(body, &list); err != nil {
+			return manifestV2{}, "", err
+		}
+		entry, err := selectManifest(list)
+		if err != nil {
+			return manifestV2{}, "", fmt.Errorf("resolving %s: %w", probe.MediaType, err)
+		}
+		listRef := ref
+		listRef.Reference = entry.Digest
+		return c.manifestAndDigest(listRef)
+	}
+
+	var m manifestV2
+	if err := json.Unmarshal(body, &m); err != nil {
+		return manifestV2{}, "", err
+	}
+	return m, digest, nil
+}
+
+// fetchBlob streams a single blob (config or layer) to a discard sink,
+// reporting progress via onProgress as bytes arrive.
+func (c *registryClient) fetchBlob(ref imageRef, layer manifestLayer, onProgress func(n int64)) (int64, error) {
+	url := fmt.Sprintf("%s/v2/%s/blobs/%s", c.baseURL(ref), ref.Repository, layer.Digest)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.doAuthenticated(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("blob fetch failed for %s: %s", layer.Digest, resp.Status)
+	}
+	var total int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			total += int64(n)
+			onProgress(int64(n))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// localBlobCache tracks which digests the native puller has already fetched
+// in this process, standing in for the daemon's content store so repeated
+// pulls of the same layer can be reported as cached.
+var localBlobCache = struct {
+	sync.Mutex
+	digests map[string]bool
+}{digests: map[string]bool{}}
+
+func isDigestCached(digest string) bool {
+	localBlobCache.Lock()
+	defer localBlobCache.Unlock()
+	return localBlobCache.digests[digest]
+}
+
+func markDigestCached(digest string) {
+	localBlobCache.Lock()
+	defer localBlobCache.Unlock()
+	if localBlobCache.digests == nil {
+		localBlobCache.digests = map[string]bool{}
+	}
+	localBlobCache.digests[digest] = true
+}
+
+// nativePullResult is the outcome of pulling an image through the native
+// registry client.
+type nativePullResult struct {
+	Layers      []LayerTiming
+	TotalBytes  int64
+	LayerCount  int
+	ConfigBytes int64
+}
+
+// pullImageNative resolves the manifest for imageURL and fetches every blob
+// concurrently, emitting a ProgressEvent per layer transition via
+// onProgress (which may be nil).
+func pullImageNative(imageURL string, maxConcurrency int, onProgress func(ProgressEvent)) (nativePullResult, error) {
+	ref := parseImageRef(imageURL)
+	result, _, err := pullRef(ref, maxConcurrency, onProgress)
+	return result, err
+}
+
+// pullRef is the core of the native puller: it resolves the manifest for an
+// already-parsed ref and fetches every blob concurrently, returning the
+// time-to-first-byte of the manifest request alongside the pull result so
+// callers comparing endpoints (e.g. mirrors) can report it.
+func pullRef(ref imageRef, maxConcurrency int, onProgress func(ProgressEvent)) (nativePullResult, time.Duration, error) {
+	if onProgress == nil {
+		onProgress = func(ProgressEvent) {}
+	}
+	client := newRegistryClient(resolveCredential(ref.Registry))
+
+	manifestStart := time.Now()
+	m, err := client.manifest(ref)
+	ttfb := time.Since(manifestStart)
+	if err != nil {
+		return nativePullResult{}, ttfb, err
+	}
+
+	layers := append([]manifestLayer{}, m.Layers...)
+	result := nativePullResult{Layers: make([]LayerTiming, len(layers)), LayerCount: len(layers)}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrency)
+	var firstErr error
+	var mu sync.Mutex
+
+	for i, layer := range layers {
+		wg.Add(1)
+		go func(i int, layer manifestLayer) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			short := shortDigest(layer.Digest)
+			start := time.Now()
+			cached := isDigestCached(layer.Digest)
+			onProgress(ProgressEvent{Layer: short, Digest: layer.Digest, Size: layer.Size, State: "starting"})
+
+			if cached {
+				onProgress(ProgressEvent{Layer: short, Digest: layer.Digest, Size: layer.Size, Bytes: layer.Size, State: "cached"})
+				mu.Lock()
+				result.Layers[i] = LayerTiming{Digest: layer.Digest, Size: layer.Size, BytesFetched: 0, ElapsedMs: 0, Cached: true}
+				mu.Unlock()
+				return
+			}
+
+			var fetched int64
+			_, err := client.fetchBlob(ref, layer, func(n int64) {
+				fetched += n
+				onProgress(ProgressEvent{Layer: short, Digest: layer.Digest, Size: layer.Size, Bytes: fetched, ElapsedMs: time.Since(start).Milliseconds(), State: "downloading"})
+			})
+			elapsed := time.Since(start)
+			if err != nil {
+				onProgress(ProgressEvent{Layer: short, Digest: layer.Digest, Size: layer.Size, Bytes: fetched, ElapsedMs: elapsed.Milliseconds(), State: "error"})
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("layer %s: %w", short, err)
+				}
+				mu.Unlock()
+				return
+			}
+			markDigestCached(layer.Digest)
+			throughput := 0.0
+			if elapsed.Seconds() > 0 {
+				throughput = (float64(fetched) / (1024 * 1024)) / elapsed.Seconds()
+			}
+			onProgress(ProgressEvent{Layer: short, Digest: layer.Digest, Size: layer.Size, Bytes: fetched, ElapsedMs: elapsed.Milliseconds(), State: "done"})
+			mu.Lock()
+			result.Layers[i] = LayerTiming{Digest: layer.Digest, Size: layer.Size, BytesFetched: fetched, ElapsedMs: elapsed.Milliseconds(), ThroughputMBs: throughput, Cached: false}
+			result.TotalBytes += fetched
+			mu.Unlock()
+		}(i, layer)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return result, ttfb, firstErr
+	}
+	result.ConfigBytes = m.Config.Size
+	return result, ttfb, nil
+}
+
+func shortDigest(digest string) string {
+	d := strings.TrimPrefix(digest, "sha256:")
+	if len(d) > 12 {
+		return d[:12]
+	}
+	return d
+}
+
+// emitProgressJSON writes a single progress event as a JSON line to stdout,
+// matching the one-event-per-layer streaming format Docker clients expect.
+func emitProgressJSON(ev ProgressEvent) {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	fmt.Println(string(b))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestParseImageRef(t *testing.T) {
+	cases := []struct {
+		image string
+		want  imageRef
+	}{
+		{"alpine", imageRef{Registry: "registry-1.docker.io", Repository: "library/alpine", Reference: "latest"}},
+		{"alpine:3.19", imageRef{Registry: "registry-1.docker.io", Repository: "library/alpine", Reference: "3.19"}},
+		{"myorg/myapp", imageRef{Registry: "registry-1.docker.io", Repository: "myorg/myapp", Reference: "latest"}},
+		{"myorg/myapp:v1", imageRef{Registry: "registry-1.docker.io", Repository: "myorg/myapp", Reference: "v1"}},
+		{"ghcr.io/myorg/myapp:v1", imageRef{Registry: "ghcr.io", Repository: "myorg/myapp", Reference: "v1"}},
+		{"localhost:5000/myapp:v1", imageRef{Registry: "localhost:5000", Repository: "myapp", Reference: "v1"}},
+		{"alpine@sha256:abc123", imageRef{Registry: "registry-1.docker.io", Repository: "library/alpine", Reference: "sha256:abc123"}},
+		{"ghcr.io/myorg/myapp@sha256:abc123", imageRef{Registry: "ghcr.io", Repository: "myorg/myapp", Reference: "sha256:abc123"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.image, func(t *testing.T) {
+			got := parseImageRef(c.image)
+			if got != c.want {
+				t.Errorf("parseImageRef(%q) = %+v, want %+v", c.image, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSelectManifest(t *testing.T) {
+	local := manifestListEntry{Digest: "sha256:local"}
+	local.Platform.OS = runtime.GOOS
+	local.Platform.Architecture = runtime.GOARCH
+
+	other := manifestListEntry{Digest: "sha256:other"}
+	other.Platform.OS = "windows"
+	other.Platform.Architecture = "amd64"
+
+	t.Run("matches local platform", func(t *testing.T) {
+		got, err := selectManifest(manifestList{Manifests: []manifestListEntry{other, local}})
+		if err != nil {
+			t.Fatalf("selectManifest returned error: %v", err)
+		}
+		if got.Digest != local.Digest {
+			t.Errorf("selectManifest returned %q, want the local-platform entry %q", got.Digest, local.Digest)
+		}
+	})
+
+	t.Run("falls back to first entry", func(t *testing.T) {
+		got, err := selectManifest(manifestList{Manifests: []manifestListEntry{other}})
+		if err != nil {
+			t.Fatalf("selectManifest returned error: %v", err)
+		}
+		if got.Digest != other.Digest {
+			t.Errorf("selectManifest returned %q, want the fallback entry %q", got.Digest, other.Digest)
+		}
+	})
+
+	t.Run("errors on empty list", func(t *testing.T) {
+		if _, err := selectManifest(manifestList{}); err == nil {
+			t.Error("selectManifest with no entries should return an error")
+		}
+	})
+}
+
+func TestParseAuthHeader(t *testing.T) {
+	header := `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/alpine:pull"`
+	got := parseAuthHeader(header)
+
+	want := map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/alpine:pull",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("parseAuthHeader()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestParseAuthHeaderIgnoresMalformedParams(t *testing.T) {
+	got := parseAuthHeader(`Bearer realm="https://auth.docker.io/token",malformed`)
+	if len(got) != 1 || got["realm"] != "https://auth.docker.io/token" {
+		t.Errorf("parseAuthHeader should skip params with no '=', got %v", got)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// humanDuration formats d the way a human would read it in a benchmark
+// summary: sub-second durations in ms, otherwise seconds or minutes.
+func humanDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	default:
+		m := int64(d.Minutes())
+		s := d - time.Duration(m)*time.Minute
+		return fmt.Sprintf("%dm%ds", m, int64(s.Seconds()))
+	}
+}
+
+// humanBytes formats a byte count using binary (KiB/MiB/GiB) units.
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// percentile returns the p-th percentile (0-100) of a slice of millisecond
+// durations, which must already be sorted ascending. Uses nearest-rank.
+func percentile(sortedMs []int64, p float64) int64 {
+	if len(sortedMs) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sortedMs)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sortedMs) {
+		rank = len(sortedMs) - 1
+	}
+	return sortedMs[rank]
+}
+
+// ImageAggregate summarizes repeated pull measurements of a single image
+// across a --repeat run.
+type ImageAggregate struct {
+	Runs         int   `json:"runs"`
+	SuccessCount int   `json:"success_count"`
+	MinMs        int64 `json:"min_ms"`
+	MaxMs        int64 `json:"max_ms"`
+	MeanMs       int64 `json:"mean_ms"`
+	StdDevMs     int64 `json:"stddev_ms"`
+	P50Ms        int64 `json:"p50_ms"`
+	P90Ms        int64 `json:"p90_ms"`
+	P99Ms        int64 `json:"p99_ms"`
+	TotalBytes   int64 `json:"total_bytes"`
+}
+
+// GlobalAggregate rolls up ImageAggregate across every image in a run.
+type GlobalAggregate struct {
+	Images       int   `json:"images"`
+	Runs         int   `json:"runs"`
+	SuccessCount int   `json:"success_count"`
+	P50Ms        int64 `json:"p50_ms"`
+	P90Ms        int64 `json:"p90_ms"`
+	P99Ms        int64 `json:"p99_ms"`
+	TotalBytes   int64 `json:"total_bytes"`
+}
+
+// computeAggregates groups results by image and computes percentile/mean/
+// stddev statistics per image plus a global rollup across all of them.
+func computeAggregates(results []Result) (map[string]ImageAggregate, GlobalAggregate) {
+	byImage := map[string][]int64{}
+	bytesByImage := map[string]int64{}
+	var allMs []int64
+	var globalBytes int64
+	attemptsByImage := map[string]int{}
+	successByImage := map[string]int{}
+
+	for _, r := range results {
+		attemptsByImage[r.Image]++
+		if !r.Success {
+			continue
+		}
+		byImage[r.Image] = append(byImage[r.Image], r.PullTimeMs)
+		bytesByImage[r.Image] += r.Bytes
+		successByImage[r.Image]++
+		allMs = append(allMs, r.PullTimeMs)
+		globalBytes += r.Bytes
+	}
+
+	perImage := map[string]ImageAggregate{}
+	for image, attempts := range attemptsByImage {
+		perImage[image] = summarize(byImage[image], attempts, successByImage[image], bytesByImage[image])
+	}
+
+	globalSummary := summarize(allMs, len(results), len(allMs), globalBytes)
+	global := GlobalAggregate{
+		Images:       len(attemptsByImage),
+		Runs:         globalSummary.Runs,
+		SuccessCount: globalSummary.SuccessCount,
+		P50Ms:        globalSummary.P50Ms,
+		P90Ms:        globalSummary.P90Ms,
+		P99Ms:        globalSummary.P99Ms,
+		TotalBytes:   globalBytes,
+	}
+	return perImage, global
+}
+
+// summarize computes percentile/mean/stddev statistics over ms (the
+// successful pull times, already a subset of attempts). Runs reflects the
+// number of attempts made, which may exceed len(ms)/successCount when some
+// of those attempts failed.
+func summarize(ms []int64, attempts, successCount int, totalBytes int64) ImageAggregate {
+	agg := ImageAggregate{Runs: attempts, SuccessCount: successCount, TotalBytes: totalBytes}
+	if len(ms) == 0 {
+		return agg
+	}
+	sorted := append([]int64{}, ms...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum int64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := float64(sum) / float64(len(sorted))
+
+	var variance float64
+	for _, v := range sorted {
+		diff := float64(v) - mean
+		variance += diff * diff
+	}
+	variance /= float64(len(sorted))
+
+	agg.MinMs = sorted[0]
+	agg.MaxMs = sorted[len(sorted)-1]
+	agg.MeanMs = int64(mean)
+	agg.StdDevMs = int64(math.Sqrt(variance))
+	agg.P50Ms = percentile(sorted, 50)
+	agg.P90Ms = percentile(sorted, 90)
+	agg.P99Ms = percentile(sorted, 99)
+	return agg
+}
+
+// sortedImageKeys returns the image names in m sorted alphabetically, so
+// summary output is stable across runs.
+func sortedImageKeys(m map[string]ImageAggregate) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,96 @@
+package main
+
+import "testing"
+
+func TestPercentile(t *testing.T) {
+	sorted := []int64{10, 20, 30, 40, 50}
+	cases := []struct {
+		p    float64
+		want int64
+	}{
+		{50, 30},
+		{90, 50},
+		{99, 50},
+		{0, 10},
+	}
+	for _, c := range cases {
+		if got := percentile(sorted, c.p); got != c.want {
+			t.Errorf("percentile(%v, %v) = %d, want %d", sorted, c.p, got, c.want)
+		}
+	}
+}
+
+func TestPercentileEmpty(t *testing.T) {
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile(nil, 50) = %d, want 0", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	agg := summarize([]int64{10, 20, 30}, 3, 3, 300)
+	if agg.Runs != 3 {
+		t.Errorf("Runs = %d, want 3", agg.Runs)
+	}
+	if agg.SuccessCount != 3 {
+		t.Errorf("SuccessCount = %d, want 3", agg.SuccessCount)
+	}
+	if agg.MinMs != 10 || agg.MaxMs != 30 {
+		t.Errorf("MinMs/MaxMs = %d/%d, want 10/30", agg.MinMs, agg.MaxMs)
+	}
+	if agg.MeanMs != 20 {
+		t.Errorf("MeanMs = %d, want 20", agg.MeanMs)
+	}
+	if agg.TotalBytes != 300 {
+		t.Errorf("TotalBytes = %d, want 300", agg.TotalBytes)
+	}
+}
+
+func TestSummarizeRunsExceedSuccesses(t *testing.T) {
+	// 2 of 5 attempts succeeded; Runs must reflect every attempt, not just
+	// the successes summarize() has timing data for.
+	agg := summarize([]int64{10, 20}, 5, 2, 100)
+	if agg.Runs != 5 {
+		t.Errorf("Runs = %d, want 5 (all attempts, not just successes)", agg.Runs)
+	}
+	if agg.SuccessCount != 2 {
+		t.Errorf("SuccessCount = %d, want 2", agg.SuccessCount)
+	}
+}
+
+func TestSummarizeAllFailed(t *testing.T) {
+	agg := summarize(nil, 3, 0, 0)
+	if agg.Runs != 3 || agg.SuccessCount != 0 {
+		t.Errorf("Runs/SuccessCount = %d/%d, want 3/0", agg.Runs, agg.SuccessCount)
+	}
+	if agg.MinMs != 0 || agg.MaxMs != 0 || agg.P50Ms != 0 {
+		t.Errorf("expected zero-valued percentiles for an all-failed image, got %+v", agg)
+	}
+}
+
+func TestComputeAggregatesCountsFailingImages(t *testing.T) {
+	results := []Result{
+		{Image: "ok", Success: true, PullTimeMs: 10, Bytes: 5},
+		{Image: "ok", Success: true, PullTimeMs: 20, Bytes: 5},
+		{Image: "broken", Success: false},
+		{Image: "broken", Success: false},
+	}
+
+	perImage, global := computeAggregates(results)
+
+	if global.Images != 2 {
+		t.Errorf("global.Images = %d, want 2 (an all-failing image still counts)", global.Images)
+	}
+
+	broken, ok := perImage["broken"]
+	if !ok {
+		t.Fatal("computeAggregates dropped an image with no successful attempts")
+	}
+	if broken.Runs != 2 || broken.SuccessCount != 0 {
+		t.Errorf("broken image Runs/SuccessCount = %d/%d, want 2/0", broken.Runs, broken.SuccessCount)
+	}
+
+	ok2 := perImage["ok"]
+	if ok2.Runs != 2 || ok2.SuccessCount != 2 {
+		t.Errorf("ok image Runs/SuccessCount = %d/%d, want 2/2", ok2.Runs, ok2.SuccessCount)
+	}
+}